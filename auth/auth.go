@@ -0,0 +1,47 @@
+/*
+ * Copyright 2011 Nan Deng
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package auth lets uniqush-push require credentials on admin actions
+// (AddPushServiceProvider/RemovePushServiceProvider) and subscriber
+// actions (Subscribe/Unsubscribe) before a processor acts on them.
+package auth
+
+import (
+	"errors"
+
+	"github.com/uniqush/uniqush-push/processor"
+)
+
+// ErrUnauthorized is returned by Authenticator.Authenticate when req's
+// credentials are missing, malformed, expired, or don't match req.Service.
+var ErrUnauthorized = errors.New("auth: unauthorized")
+
+// Principal identifies who a request's credentials were issued to. It is
+// logged on every authentication attempt for audit purposes.
+type Principal struct {
+	Name    string
+	Service string
+}
+
+// Authenticator verifies a Request's credentials before a processor calls
+// Validate. Implementations must be safe for concurrent use.
+type Authenticator interface {
+	// Authenticate inspects req.AuthToken/req.AuthMetadata and returns the
+	// Principal the request authenticated as, or ErrUnauthorized (wrapped
+	// or not) if it didn't.
+	Authenticate(req *processor.Request) (Principal, error)
+}
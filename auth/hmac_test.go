@@ -0,0 +1,108 @@
+/*
+ * Copyright 2011 Nan Deng
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package auth
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/uniqush/uniqush-push/processor"
+)
+
+func signedRequest(secret []byte, service string, action processor.Action, ts time.Time, nonce string) *processor.Request {
+	req := processor.NewRequest("req-1", action)
+	req.Service = service
+	timestamp := strconv.FormatInt(ts.Unix(), 10)
+	req.AuthMetadata = map[string]string{"timestamp": timestamp, "nonce": nonce}
+	req.AuthToken = Sign(secret, service, action.String(), timestamp, nonce)
+	return req
+}
+
+func TestHMACAuthenticatorAcceptsFreshRequest(t *testing.T) {
+	secret := []byte("s3cret")
+	h := NewHMACAuthenticator(map[string][]byte{"svc": secret}, time.Minute)
+
+	req := signedRequest(secret, "svc", processor.SubscribeAction, time.Now(), "nonce-1")
+	principal, err := h.Authenticate(req)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if principal.Service != "svc" {
+		t.Fatalf("principal.Service: got %q, want %q", principal.Service, "svc")
+	}
+}
+
+func TestHMACAuthenticatorRejectsReplayedNonce(t *testing.T) {
+	secret := []byte("s3cret")
+	h := NewHMACAuthenticator(map[string][]byte{"svc": secret}, time.Minute)
+
+	req := signedRequest(secret, "svc", processor.SubscribeAction, time.Now(), "nonce-1")
+	if _, err := h.Authenticate(req); err != nil {
+		t.Fatalf("first Authenticate: %v", err)
+	}
+
+	replay := signedRequest(secret, "svc", processor.SubscribeAction, time.Now(), "nonce-1")
+	if _, err := h.Authenticate(replay); err != ErrUnauthorized {
+		t.Fatalf("replayed nonce: got %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestHMACAuthenticatorRejectsExpiredTimestamp(t *testing.T) {
+	secret := []byte("s3cret")
+	h := NewHMACAuthenticator(map[string][]byte{"svc": secret}, time.Minute)
+
+	req := signedRequest(secret, "svc", processor.SubscribeAction, time.Now().Add(-2*time.Minute), "nonce-1")
+	if _, err := h.Authenticate(req); err != ErrUnauthorized {
+		t.Fatalf("timestamp outside the replay window: got %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestHMACAuthenticatorRejectsBadSignature(t *testing.T) {
+	secret := []byte("s3cret")
+	h := NewHMACAuthenticator(map[string][]byte{"svc": secret}, time.Minute)
+
+	req := signedRequest([]byte("wrong-secret"), "svc", processor.SubscribeAction, time.Now(), "nonce-1")
+	if _, err := h.Authenticate(req); err != ErrUnauthorized {
+		t.Fatalf("bad signature: got %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestHMACAuthenticatorRejectsUnknownService(t *testing.T) {
+	h := NewHMACAuthenticator(map[string][]byte{"svc": []byte("s3cret")}, time.Minute)
+
+	req := signedRequest([]byte("s3cret"), "other", processor.SubscribeAction, time.Now(), "nonce-1")
+	if _, err := h.Authenticate(req); err != ErrUnauthorized {
+		t.Fatalf("unknown service: got %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestHMACAuthenticatorSameNonceDifferentServicesAllowed(t *testing.T) {
+	secrets := map[string][]byte{"svc-a": []byte("secret-a"), "svc-b": []byte("secret-b")}
+	h := NewHMACAuthenticator(secrets, time.Minute)
+
+	reqA := signedRequest(secrets["svc-a"], "svc-a", processor.SubscribeAction, time.Now(), "shared-nonce")
+	reqB := signedRequest(secrets["svc-b"], "svc-b", processor.SubscribeAction, time.Now(), "shared-nonce")
+
+	if _, err := h.Authenticate(reqA); err != nil {
+		t.Fatalf("svc-a Authenticate: %v", err)
+	}
+	if _, err := h.Authenticate(reqB); err != nil {
+		t.Fatalf("svc-b Authenticate with the same nonce as svc-a: %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright 2011 Nan Deng
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// jwk is one entry of a JSON Web Key Set, restricted to the RSA fields
+// uniqush-push's JWT verification needs.
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+// JWKSCache fetches a JSON Web Key Set from URL and caches the decoded RSA
+// public keys for RefreshInterval before fetching again. It implements
+// KeySet, so a JWTAuthenticator can be pointed at any standard JWKS
+// endpoint without uniqush-push hard-coding a key.
+type JWKSCache struct {
+	URL             string
+	RefreshInterval time.Duration
+	HTTPClient      *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+// NewJWKSCache builds a JWKSCache. refresh controls how often the keys are
+// re-fetched; a refresh of 0 disables caching (fetch every call).
+func NewJWKSCache(url string, refresh time.Duration) *JWKSCache {
+	return &JWKSCache{URL: url, RefreshInterval: refresh, HTTPClient: http.DefaultClient}
+}
+
+func (c *JWKSCache) KeyFor(kid string) (*rsa.PublicKey, error) {
+	c.mu.Lock()
+	keys, fetchedAt := c.keys, c.fetchedAt
+	c.mu.Unlock()
+
+	if keys == nil || time.Since(fetchedAt) > c.RefreshInterval {
+		fresh, err := c.fetch()
+		if err != nil {
+			// Serve stale keys rather than fail every request because one
+			// refresh hiccuped.
+			if key, ok := keys[kid]; ok {
+				return key, nil
+			}
+			return nil, err
+		}
+		keys = fresh
+		c.mu.Lock()
+		c.keys, c.fetchedAt = keys, time.Now()
+		c.mu.Unlock()
+	}
+
+	key, ok := keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("auth: no JWKS key for kid %q", kid)
+	}
+	return key, nil
+}
+
+func (c *JWKSCache) fetch() (map[string]*rsa.PublicKey, error) {
+	resp, err := c.HTTPClient.Get(c.URL)
+	if err != nil {
+		return nil, fmt.Errorf("auth: fetching JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var set jwkSet
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("auth: decoding JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		pub, err := rsaPublicKeyFromJWK(k)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+	return keys, nil
+}
+
+func rsaPublicKeyFromJWK(k jwk) (*rsa.PublicKey, error) {
+	nBytes, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("auth: decoding JWK modulus: %w", err)
+	}
+	eBytes, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("auth: decoding JWK exponent: %w", err)
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(nBytes),
+		E: int(new(big.Int).SetBytes(eBytes).Int64()),
+	}, nil
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2011 Nan Deng
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package auth
+
+import (
+	"crypto/rsa"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/uniqush/uniqush-push/processor"
+)
+
+// KeySet resolves the public key a JWT's "kid" header was signed with.
+// JWKSCache (jwks.go) is the configurable, HTTP-backed implementation;
+// tests can supply a static map instead.
+type KeySet interface {
+	KeyFor(kid string) (*rsa.PublicKey, error)
+}
+
+// JWTAuthenticator verifies a JWT bearer token: signature against Keys,
+// "aud" against Audience, "exp"/"nbf" against the current time (both via
+// jwt-go's default validation), and a "service" claim that must equal
+// req.Service.
+type JWTAuthenticator struct {
+	Keys     KeySet
+	Audience string
+}
+
+func NewJWTAuthenticator(keys KeySet, audience string) *JWTAuthenticator {
+	return &JWTAuthenticator{Keys: keys, Audience: audience}
+}
+
+func (j *JWTAuthenticator) Authenticate(req *processor.Request) (Principal, error) {
+	if req.AuthToken == "" {
+		return Principal{}, ErrUnauthorized
+	}
+
+	claims := jwt.MapClaims{}
+	parser := jwt.NewParser(jwt.WithAudience(j.Audience), jwt.WithExpirationRequired())
+	token, err := parser.ParseWithClaims(req.AuthToken, claims, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("auth: unexpected signing method %v", t.Header["alg"])
+		}
+		kid, _ := t.Header["kid"].(string)
+		return j.Keys.KeyFor(kid)
+	})
+	if err != nil || !token.Valid {
+		return Principal{}, ErrUnauthorized
+	}
+
+	service, _ := claims["service"].(string)
+	if service == "" || service != req.Service {
+		return Principal{}, ErrUnauthorized
+	}
+
+	sub, _ := claims["sub"].(string)
+	return Principal{Name: sub, Service: service}, nil
+}
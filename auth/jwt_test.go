@@ -0,0 +1,161 @@
+/*
+ * Copyright 2011 Nan Deng
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/uniqush/uniqush-push/processor"
+)
+
+type staticKeySet map[string]*rsa.PublicKey
+
+func (s staticKeySet) KeyFor(kid string) (*rsa.PublicKey, error) {
+	key, ok := s[kid]
+	if !ok {
+		return nil, ErrUnauthorized
+	}
+	return key, nil
+}
+
+func signJWT(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+	return signed
+}
+
+func newJWTFixture(t *testing.T) (*rsa.PrivateKey, *JWTAuthenticator) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	keys := staticKeySet{"kid-1": &key.PublicKey}
+	return key, NewJWTAuthenticator(keys, "uniqush-push")
+}
+
+func TestJWTAuthenticatorAcceptsValidToken(t *testing.T) {
+	key, authn := newJWTFixture(t)
+	token := signJWT(t, key, "kid-1", jwt.MapClaims{
+		"aud":     "uniqush-push",
+		"exp":     time.Now().Add(time.Hour).Unix(),
+		"service": "svc",
+		"sub":     "client-1",
+	})
+
+	req := processor.NewRequest("req-1", processor.SubscribeAction)
+	req.Service = "svc"
+	req.AuthToken = token
+
+	principal, err := authn.Authenticate(req)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if principal.Name != "client-1" || principal.Service != "svc" {
+		t.Fatalf("principal: got %+v, want Name=client-1 Service=svc", principal)
+	}
+}
+
+func TestJWTAuthenticatorRejectsExpiredToken(t *testing.T) {
+	key, authn := newJWTFixture(t)
+	token := signJWT(t, key, "kid-1", jwt.MapClaims{
+		"aud":     "uniqush-push",
+		"exp":     time.Now().Add(-time.Hour).Unix(),
+		"service": "svc",
+	})
+
+	req := processor.NewRequest("req-1", processor.SubscribeAction)
+	req.Service = "svc"
+	req.AuthToken = token
+
+	if _, err := authn.Authenticate(req); err != ErrUnauthorized {
+		t.Fatalf("expired token: got %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestJWTAuthenticatorRejectsWrongAudience(t *testing.T) {
+	key, authn := newJWTFixture(t)
+	token := signJWT(t, key, "kid-1", jwt.MapClaims{
+		"aud":     "someone-else",
+		"exp":     time.Now().Add(time.Hour).Unix(),
+		"service": "svc",
+	})
+
+	req := processor.NewRequest("req-1", processor.SubscribeAction)
+	req.Service = "svc"
+	req.AuthToken = token
+
+	if _, err := authn.Authenticate(req); err != ErrUnauthorized {
+		t.Fatalf("wrong audience: got %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestJWTAuthenticatorRejectsServiceClaimMismatch(t *testing.T) {
+	key, authn := newJWTFixture(t)
+	token := signJWT(t, key, "kid-1", jwt.MapClaims{
+		"aud":     "uniqush-push",
+		"exp":     time.Now().Add(time.Hour).Unix(),
+		"service": "svc-a",
+	})
+
+	req := processor.NewRequest("req-1", processor.SubscribeAction)
+	req.Service = "svc-b"
+	req.AuthToken = token
+
+	if _, err := authn.Authenticate(req); err != ErrUnauthorized {
+		t.Fatalf("service claim mismatch: got %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestJWTAuthenticatorRejectsUnknownKeyID(t *testing.T) {
+	key, authn := newJWTFixture(t)
+	token := signJWT(t, key, "kid-unknown", jwt.MapClaims{
+		"aud":     "uniqush-push",
+		"exp":     time.Now().Add(time.Hour).Unix(),
+		"service": "svc",
+	})
+
+	req := processor.NewRequest("req-1", processor.SubscribeAction)
+	req.Service = "svc"
+	req.AuthToken = token
+
+	if _, err := authn.Authenticate(req); err != ErrUnauthorized {
+		t.Fatalf("unknown kid: got %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestJWTAuthenticatorRejectsMissingToken(t *testing.T) {
+	_, authn := newJWTFixture(t)
+
+	req := processor.NewRequest("req-1", processor.SubscribeAction)
+	req.Service = "svc"
+
+	if _, err := authn.Authenticate(req); err != ErrUnauthorized {
+		t.Fatalf("missing token: got %v, want ErrUnauthorized", err)
+	}
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright 2011 Nan Deng
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/uniqush/uniqush-push/auth"
+	"github.com/uniqush/uniqush-push/log"
+
+	. "github.com/uniqush/uniqush-push/processor"
+)
+
+// authSetter is embedded by every processor that requires credentials,
+// following the same pattern as logSetter and databaseSetter. A nil
+// authenticator (the zero value) disables authentication entirely, so
+// deployments that don't configure one see no behavior change.
+type authSetter struct {
+	authenticator auth.Authenticator
+}
+
+func (a *authSetter) SetAuthenticator(authenticator auth.Authenticator) {
+	a.authenticator = authenticator
+}
+
+// authSettable is implemented by every processor that embeds authSetter.
+// BuildDispatchTable type-asserts against it, the same way it does for
+// metricsSettable, so a configured authenticator actually reaches the
+// processors that require credentials instead of staying nil.
+type authSettable interface {
+	SetAuthenticator(authenticator auth.Authenticator)
+}
+
+// authenticateOrRespond authenticates req when an authenticator is
+// configured, audit-logs the outcome, and on failure responds to req with
+// an Unauthorized error and returns false. Callers still defer
+// req.Finish(); this only decides whether Process should return early.
+func (a *authSetter) authenticateOrRespond(logger log.Logger, action string, req *Request) bool {
+	if a.authenticator == nil {
+		return true
+	}
+
+	principal, err := a.authenticator.Authenticate(req)
+	if err != nil {
+		logger.Warn("request authentication failed",
+			log.RequestId(req.ID), log.Action(action), log.Service(req.Service), log.Err(err))
+		req.Respond(fmt.Errorf("[Unauthorized] RequestId=%v %v", req.ID, err))
+		return false
+	}
+
+	logger.Info("request authenticated",
+		log.RequestId(req.ID), log.Action(action), log.Service(req.Service),
+		log.F("Principal", principal.Name))
+	return true
+}
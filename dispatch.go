@@ -0,0 +1,74 @@
+/*
+ * Copyright 2011 Nan Deng
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"github.com/uniqush/uniqush-push/auth"
+	"github.com/uniqush/uniqush-push/gopool"
+	"github.com/uniqush/uniqush-push/log"
+
+	. "github.com/uniqush/pushdb"
+	. "github.com/uniqush/uniqush-push/processor"
+)
+
+// BuildDispatchTable builds main's Action -> RequestProcessor table by
+// iterating the processor registry instead of calling
+// NewAddPushServiceProviderProcessor/NewSubscribeProcessor/etc. directly,
+// so a plugin registered via LoadPlugins is wired up exactly like an
+// in-tree processor. metrics, authenticator and pools are all optional; a
+// nil metrics/authenticator or a missing pools entry for an action just
+// skips that middleware.
+func BuildDispatchTable(logger log.Logger, dbfront PushDatabase, metrics *MetricsRegistry, authenticator auth.Authenticator, pools map[Action]*gopool.Pool) map[Action]RequestProcessor {
+	table := make(map[Action]RequestProcessor)
+	for _, action := range Actions() {
+		factory, ok := Lookup(action)
+		if !ok {
+			continue
+		}
+		p := factory(logger, dbfront)
+
+		if authenticator != nil {
+			if as, ok := p.(authSettable); ok {
+				as.SetAuthenticator(authenticator)
+			}
+		}
+
+		// WithPool goes outermost so WithMetrics only starts timing once a
+		// worker actually picks the request up; stacked the other way
+		// around, process_duration_seconds and requests_in_flight would
+		// measure pool.Submit's instant return instead of real work.
+		var mws []ProcessorMiddleware
+		if pool, ok := pools[action]; ok {
+			mws = append(mws, func(inner RequestProcessor) RequestProcessor {
+				return WithPool(inner, pool)
+			})
+		}
+		if metrics != nil {
+			if ms, ok := p.(metricsSettable); ok {
+				ms.SetMetrics(metrics, action.String())
+			}
+			action := action // capture for the closure
+			mws = append(mws, func(inner RequestProcessor) RequestProcessor {
+				return WithMetrics(inner, action.String(), metrics)
+			})
+		}
+
+		table[action] = Chain(p, mws...)
+	}
+	return table
+}
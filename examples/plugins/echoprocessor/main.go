@@ -0,0 +1,63 @@
+/*
+ * Copyright 2011 Nan Deng
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package main is an example out-of-tree RequestProcessor, demonstrating
+// the symbols LoadPlugins requires. Build it with:
+//
+//	go build -buildmode=plugin -o echoprocessor.so ./examples/plugins/echoprocessor
+//
+// then drop echoprocessor.so in uniqush-push's configured plugin
+// directory. It registers a new Action that just logs the request and
+// responds with success, the simplest possible stand-in for something
+// like bulk-subscribe or tag-based routing.
+package main
+
+import (
+	"github.com/uniqush/pushdb"
+	"github.com/uniqush/uniqush-push/log"
+	"github.com/uniqush/uniqush-push/processor"
+)
+
+// EchoAction is an Action value outside the range uniqush-push's built-ins
+// use, the same way any out-of-tree plugin picks an unused Action for its
+// own purpose.
+const EchoAction processor.Action = 1000
+
+// Action is looked up by LoadPlugins to know which Action this plugin
+// registers a RequestProcessor for.
+var Action = EchoAction
+
+type echoProcessor struct {
+	logger log.Logger
+}
+
+func (e *echoProcessor) SetLogger(logger log.Logger) {
+	e.logger = logger
+}
+
+func (e *echoProcessor) Process(req *processor.Request) {
+	defer req.Finish()
+	e.logger.Info("echo request", log.RequestId(req.ID), log.Service(req.Service))
+	req.Respond(nil)
+}
+
+// NewProcessor is looked up by LoadPlugins and must match processor.Factory.
+func NewProcessor(logger log.Logger, dbfront pushdb.PushDatabase) processor.RequestProcessor {
+	return &echoProcessor{logger: logger}
+}
+
+func main() {}
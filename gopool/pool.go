@@ -0,0 +1,149 @@
+/*
+ * Copyright 2011 Nan Deng
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package gopool implements a fixed-size worker pool with a bounded queue,
+// so uniqush-push can bound the concurrency and memory a burst of requests
+// causes instead of spawning one goroutine per request.
+package gopool
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrQueueFull is returned by Submit when the pool's queue is full and its
+// FullPolicy is PolicyDrop.
+var ErrQueueFull = errors.New("gopool: queue full")
+
+// ErrStopped is returned by Submit once the pool has started shutting down.
+var ErrStopped = errors.New("gopool: pool stopped")
+
+// FullPolicy controls what Submit does when the queue is already full.
+type FullPolicy int
+
+const (
+	// PolicyBlock makes Submit block until there is room in the queue.
+	PolicyBlock FullPolicy = iota
+	// PolicyDrop makes Submit return ErrQueueFull immediately.
+	PolicyDrop
+	// PolicySpillover makes Submit hand the task to the overflow pool
+	// passed to New instead.
+	PolicySpillover
+)
+
+// Pool is a fixed-size worker pool with a bounded task queue.
+type Pool struct {
+	tasks    chan func()
+	policy   FullPolicy
+	overflow *Pool
+
+	wg       sync.WaitGroup // workers
+	inflight sync.WaitGroup // submitted-but-not-yet-finished tasks
+
+	mu      sync.Mutex
+	stopped bool
+}
+
+// New starts a Pool with the given number of workers and queue capacity.
+// policy governs Submit's behavior once the queue is full; when policy is
+// PolicySpillover, overflow must be non-nil and is used as the secondary,
+// typically slower, pool.
+func New(workers, queueSize int, policy FullPolicy, overflow *Pool) *Pool {
+	p := &Pool{
+		tasks:    make(chan func(), queueSize),
+		policy:   policy,
+		overflow: overflow,
+	}
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go p.worker()
+	}
+	return p
+}
+
+func (p *Pool) worker() {
+	defer p.wg.Done()
+	for task := range p.tasks {
+		task()
+		p.inflight.Done()
+	}
+}
+
+// Submit enqueues task for execution on a worker goroutine. It returns
+// ErrStopped once Shutdown has been called, ErrQueueFull if the queue is
+// full and the policy is PolicyDrop, or the overflow pool's Submit result
+// if the policy is PolicySpillover.
+func (p *Pool) Submit(task func()) error {
+	p.mu.Lock()
+	if p.stopped {
+		p.mu.Unlock()
+		return ErrStopped
+	}
+	p.inflight.Add(1)
+	p.mu.Unlock()
+
+	switch p.policy {
+	case PolicyDrop:
+		select {
+		case p.tasks <- task:
+			return nil
+		default:
+			p.inflight.Done()
+			return ErrQueueFull
+		}
+	case PolicySpillover:
+		select {
+		case p.tasks <- task:
+			return nil
+		default:
+			p.inflight.Done()
+			return p.overflow.Submit(task)
+		}
+	default: // PolicyBlock
+		p.tasks <- task
+		return nil
+	}
+}
+
+// Shutdown stops the pool from accepting new work, waits for all submitted
+// tasks to finish (their Finish/Respond has happened), then returns. It
+// returns ctx.Err() if ctx is done before drain completes.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if p.stopped {
+		p.mu.Unlock()
+		return nil
+	}
+	p.stopped = true
+	p.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		p.inflight.Wait()
+		close(p.tasks)
+		p.wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,161 @@
+/*
+ * Copyright 2011 Nan Deng
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package gopool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPoolRunsSubmittedTasks(t *testing.T) {
+	p := New(2, 4, PolicyBlock, nil)
+	defer p.Shutdown(context.Background())
+
+	var n int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		if err := p.Submit(func() {
+			atomic.AddInt32(&n, 1)
+			wg.Done()
+		}); err != nil {
+			t.Fatalf("Submit: %v", err)
+		}
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&n); got != 10 {
+		t.Fatalf("tasks run: got %d, want 10", got)
+	}
+}
+
+func TestPoolPolicyDropRejectsWhenFull(t *testing.T) {
+	block := make(chan struct{})
+	p := New(1, 1, PolicyDrop, nil)
+	defer p.Shutdown(context.Background())
+	defer close(block)
+
+	// Occupy the single worker, then fill the queue, so the next Submit has
+	// nowhere to go.
+	started := make(chan struct{})
+	if err := p.Submit(func() { close(started); <-block }); err != nil {
+		t.Fatalf("Submit (worker): %v", err)
+	}
+	<-started
+	if err := p.Submit(func() { <-block }); err != nil {
+		t.Fatalf("Submit (queue slot): %v", err)
+	}
+
+	if err := p.Submit(func() {}); err != ErrQueueFull {
+		t.Fatalf("Submit over capacity: got %v, want ErrQueueFull", err)
+	}
+}
+
+func TestPoolPolicySpilloverUsesOverflow(t *testing.T) {
+	overflow := New(1, 1, PolicyBlock, nil)
+	defer overflow.Shutdown(context.Background())
+
+	block := make(chan struct{})
+	p := New(1, 1, PolicySpillover, overflow)
+	defer p.Shutdown(context.Background())
+	defer close(block)
+
+	started := make(chan struct{})
+	if err := p.Submit(func() { close(started); <-block }); err != nil {
+		t.Fatalf("Submit (worker): %v", err)
+	}
+	<-started
+	if err := p.Submit(func() { <-block }); err != nil {
+		t.Fatalf("Submit (queue slot): %v", err)
+	}
+
+	var ran int32
+	if err := p.Submit(func() { atomic.AddInt32(&ran, 1) }); err != nil {
+		t.Fatalf("Submit over capacity with spillover: %v", err)
+	}
+
+	deadline := time.After(time.Second)
+	for atomic.LoadInt32(&ran) == 0 {
+		select {
+		case <-deadline:
+			t.Fatal("spilled-over task never ran on the overflow pool")
+		case <-time.After(time.Millisecond):
+		}
+	}
+}
+
+func TestPoolSubmitAfterShutdown(t *testing.T) {
+	p := New(1, 1, PolicyBlock, nil)
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	if err := p.Submit(func() {}); err != ErrStopped {
+		t.Fatalf("Submit after Shutdown: got %v, want ErrStopped", err)
+	}
+}
+
+func TestPoolShutdownWaitsForInFlight(t *testing.T) {
+	p := New(1, 1, PolicyBlock, nil)
+
+	var finished int32
+	release := make(chan struct{})
+	if err := p.Submit(func() {
+		<-release
+		atomic.AddInt32(&finished, 1)
+	}); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- p.Shutdown(context.Background()) }()
+
+	select {
+	case <-done:
+		t.Fatal("Shutdown returned before the in-flight task finished")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if atomic.LoadInt32(&finished) != 1 {
+		t.Fatal("Shutdown returned without the task having run")
+	}
+}
+
+func TestPoolShutdownRespectsContext(t *testing.T) {
+	p := New(1, 1, PolicyBlock, nil)
+
+	// Unblock the worker so the pool doesn't leak past the test.
+	release := make(chan struct{})
+	defer close(release)
+	if err := p.Submit(func() { <-release }); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := p.Shutdown(ctx); err != ctx.Err() {
+		t.Fatalf("Shutdown with an expiring context: got %v, want ctx.Err()", err)
+	}
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright 2011 Nan Deng
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package log
+
+import "time"
+
+// Level is a logging threshold, ordered from most to least verbose.
+type Level int
+
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+// ParseLevel converts a config string ("debug", "info", "warn", "error")
+// into a Level, defaulting to InfoLevel for anything unrecognized.
+func ParseLevel(s string) Level {
+	switch s {
+	case "debug":
+		return DebugLevel
+	case "warn":
+		return WarnLevel
+	case "error":
+		return ErrorLevel
+	default:
+		return InfoLevel
+	}
+}
+
+// Encoding selects how log events are rendered.
+type Encoding int
+
+const (
+	// JSONEncoding renders one JSON object per line.
+	JSONEncoding Encoding = iota
+	// ConsoleEncoding renders a human-readable line, for interactive use.
+	ConsoleEncoding
+)
+
+// Rotation configures on-disk rotation of the log output file. It is
+// ignored when OutputPath is empty (stderr is never rotated).
+type Rotation struct {
+	MaxSizeMB  int // rotate once the active file reaches this size
+	MaxBackups int // number of rotated files to keep
+	MaxAgeDays int // delete rotated files older than this
+	Compress   bool
+}
+
+// ServiceOverride pins a minimum level for one service's events,
+// independent of the global Level.
+type ServiceOverride struct {
+	Service string
+	Level   Level
+}
+
+// Sampling thins out high-volume repeated log lines: within each Interval
+// window, of every Tick occurrences of the same message (at any level, not
+// just DebugLevel), only the first one is kept. A Tick of 0 or 1 disables
+// sampling. Interval defaults to one second when Tick enables sampling but
+// Interval is left zero.
+type Sampling struct {
+	Tick     int
+	Interval time.Duration
+}
+
+// Config is loaded once in main and used to build the process-wide Logger.
+type Config struct {
+	Level      Level
+	Encoding   Encoding
+	OutputPath string // empty means stderr
+	Rotation   Rotation
+	Overrides  []ServiceOverride
+	Sampling   Sampling
+}
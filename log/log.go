@@ -0,0 +1,71 @@
+/*
+ * Copyright 2011 Nan Deng
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package log is uniqush-push's structured logging abstraction. It replaces
+// the ad-hoc Errorf/Infof string concatenation processors used to do with a
+// small interface that always emits key/value fields, so log lines stay
+// greppable and parseable regardless of which backend renders them.
+package log
+
+// Well-known field keys shared by every processor. Using constants here
+// keeps call sites and any downstream log-processing pipeline in sync.
+const (
+	FieldRequestId           = "RequestId"
+	FieldService             = "Service"
+	FieldSubscriber          = "Subscriber"
+	FieldPushServiceProvider = "PushServiceProvider"
+	FieldDeliveryPoint       = "DeliveryPoint"
+	FieldAction              = "Action"
+	FieldDurationMs          = "DurationMs"
+	FieldError               = "Error"
+)
+
+// Field is a single structured key/value pair attached to a log event.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// F builds a Field. It is the general-purpose constructor; the typed
+// helpers below (RequestId, Service, ...) exist for the fields processors
+// reach for most often.
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+func RequestId(v interface{}) Field      { return F(FieldRequestId, v) }
+func Service(v string) Field             { return F(FieldService, v) }
+func Subscriber(v string) Field          { return F(FieldSubscriber, v) }
+func PushServiceProvider(v string) Field { return F(FieldPushServiceProvider, v) }
+func DeliveryPoint(v string) Field       { return F(FieldDeliveryPoint, v) }
+func Action(v string) Field              { return F(FieldAction, v) }
+func DurationMs(v int64) Field           { return F(FieldDurationMs, v) }
+func Err(err error) Field                { return F(FieldError, err) }
+
+// Logger is the structured logging interface every processor depends on.
+// Implementations must be safe for concurrent use.
+type Logger interface {
+	Debug(msg string, fields ...Field)
+	Info(msg string, fields ...Field)
+	Warn(msg string, fields ...Field)
+	Error(msg string, fields ...Field)
+
+	// WithService returns a Logger whose level is controlled by any
+	// per-service override in the Config, and whose events are tagged with
+	// FieldService automatically.
+	WithService(service string) Logger
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright 2011 Nan Deng
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package log
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// textLogger is a dependency-free fallback Logger: one "key=value" line per
+// event to an io.Writer. It exists for environments that don't want the
+// zap dependency; callers build it with NewText, since New always builds
+// the zap-backed logger.
+type textLogger struct {
+	mu      sync.Mutex
+	out     io.Writer
+	service string
+	cfg     *Config
+}
+
+// NewText builds a textLogger writing to w (os.Stderr if w is nil).
+func NewText(cfg Config, w io.Writer) Logger {
+	if w == nil {
+		w = os.Stderr
+	}
+	c := cfg
+	return &textLogger{out: w, cfg: &c}
+}
+
+func (t *textLogger) effectiveLevel() Level {
+	for _, o := range t.cfg.Overrides {
+		if o.Service == t.service {
+			return o.Level
+		}
+	}
+	return t.cfg.Level
+}
+
+func (t *textLogger) log(level Level, levelName, msg string, fields ...Field) {
+	if level < t.effectiveLevel() {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Fprintf(t.out, "%s\tlevel=%s\tmsg=%q", time.Now().Format(time.RFC3339), levelName, msg)
+	if t.service != "" {
+		fmt.Fprintf(t.out, "\t%s=%s", FieldService, t.service)
+	}
+	for _, f := range fields {
+		fmt.Fprintf(t.out, "\t%s=%v", f.Key, f.Value)
+	}
+	fmt.Fprintln(t.out)
+}
+
+func (t *textLogger) Debug(msg string, fields ...Field) { t.log(DebugLevel, "debug", msg, fields...) }
+func (t *textLogger) Info(msg string, fields ...Field)  { t.log(InfoLevel, "info", msg, fields...) }
+func (t *textLogger) Warn(msg string, fields ...Field)  { t.log(WarnLevel, "warn", msg, fields...) }
+func (t *textLogger) Error(msg string, fields ...Field) { t.log(ErrorLevel, "error", msg, fields...) }
+
+func (t *textLogger) WithService(service string) Logger {
+	return &textLogger{out: t.out, service: service, cfg: t.cfg}
+}
@@ -0,0 +1,142 @@
+/*
+ * Copyright 2011 Nan Deng
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package log
+
+import (
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	natural "gopkg.in/natefinch/lumberjack.v2"
+)
+
+// zapLogger is the default Logger, backed by zap for low-overhead
+// structured output.
+type zapLogger struct {
+	base    *zap.Logger
+	service string
+	cfg     *Config
+}
+
+// New builds the process-wide Logger from cfg. Callers typically build this
+// once in main and share it across every processor.
+func New(cfg Config) (Logger, error) {
+	encoderCfg := zap.NewProductionEncoderConfig()
+	encoderCfg.TimeKey = "ts"
+	encoderCfg.MessageKey = "msg"
+
+	var encoder zapcore.Encoder
+	if cfg.Encoding == ConsoleEncoding {
+		encoder = zapcore.NewConsoleEncoder(encoderCfg)
+	} else {
+		encoder = zapcore.NewJSONEncoder(encoderCfg)
+	}
+
+	writer := newWriter(cfg)
+	// The core must let through the most verbose level any service override
+	// asks for; effectiveLevel then filters each event per service.
+	minLevel := cfg.Level
+	for _, o := range cfg.Overrides {
+		if o.Level < minLevel {
+			minLevel = o.Level
+		}
+	}
+	level := toZapLevel(minLevel)
+	core := zapcore.NewCore(encoder, writer, level)
+	if cfg.Sampling.Tick > 1 {
+		interval := cfg.Sampling.Interval
+		if interval <= 0 {
+			interval = time.Second
+		}
+		core = zapcore.NewSamplerWithOptions(core, interval, 1, cfg.Sampling.Tick)
+	}
+
+	base := zap.New(core)
+	c := cfg
+	return &zapLogger{base: base, cfg: &c}, nil
+}
+
+// newWriter returns the zapcore.WriteSyncer New's core writes through. An
+// empty cfg.OutputPath means stderr, matching NewText's fallback.
+func newWriter(cfg Config) zapcore.WriteSyncer {
+	if cfg.OutputPath == "" {
+		return zapcore.Lock(os.Stderr)
+	}
+	return zapcore.AddSync(&natural.Logger{
+		Filename:   cfg.OutputPath,
+		MaxSize:    cfg.Rotation.MaxSizeMB,
+		MaxBackups: cfg.Rotation.MaxBackups,
+		MaxAge:     cfg.Rotation.MaxAgeDays,
+		Compress:   cfg.Rotation.Compress,
+	})
+}
+
+func toZapLevel(l Level) zapcore.Level {
+	switch l {
+	case DebugLevel:
+		return zapcore.DebugLevel
+	case WarnLevel:
+		return zapcore.WarnLevel
+	case ErrorLevel:
+		return zapcore.ErrorLevel
+	default:
+		return zapcore.InfoLevel
+	}
+}
+
+func (z *zapLogger) effectiveLevel() Level {
+	for _, o := range z.cfg.Overrides {
+		if o.Service == z.service {
+			return o.Level
+		}
+	}
+	return z.cfg.Level
+}
+
+func (z *zapLogger) log(level Level, msg string, fields ...Field) {
+	if level < z.effectiveLevel() {
+		return
+	}
+	zfields := make([]zap.Field, 0, len(fields)+1)
+	if z.service != "" {
+		zfields = append(zfields, zap.String(FieldService, z.service))
+	}
+	for _, f := range fields {
+		zfields = append(zfields, zap.Any(f.Key, f.Value))
+	}
+	switch level {
+	case DebugLevel:
+		z.base.Debug(msg, zfields...)
+	case WarnLevel:
+		z.base.Warn(msg, zfields...)
+	case ErrorLevel:
+		z.base.Error(msg, zfields...)
+	default:
+		z.base.Info(msg, zfields...)
+	}
+}
+
+func (z *zapLogger) Debug(msg string, fields ...Field) { z.log(DebugLevel, msg, fields...) }
+func (z *zapLogger) Info(msg string, fields ...Field)  { z.log(InfoLevel, msg, fields...) }
+func (z *zapLogger) Warn(msg string, fields ...Field)  { z.log(WarnLevel, msg, fields...) }
+func (z *zapLogger) Error(msg string, fields ...Field) { z.log(ErrorLevel, msg, fields...) }
+
+func (z *zapLogger) WithService(service string) Logger {
+	return &zapLogger{base: z.base, service: service, cfg: z.cfg}
+}
@@ -0,0 +1,162 @@
+/*
+ * Copyright 2011 Nan Deng
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	. "github.com/uniqush/uniqush-push/processor"
+)
+
+// MetricsRegistry owns every Prometheus collector uniqush-push exposes and
+// the HTTP listener that serves them at /metrics.
+type MetricsRegistry struct {
+	requestsTotal   *prometheus.CounterVec
+	successTotal    *prometheus.CounterVec
+	validationFails *prometheus.CounterVec
+	databaseFails   *prometheus.CounterVec
+	processDuration *prometheus.HistogramVec
+	inFlight        *prometheus.GaugeVec
+}
+
+// NewMetricsRegistry creates and registers the uniqush-push collector set
+// with the default Prometheus registry.
+func NewMetricsRegistry() *MetricsRegistry {
+	m := &MetricsRegistry{
+		requestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "uniqush_push",
+			Name:      "requests_total",
+			Help:      "Total number of requests handled by a RequestProcessor.",
+		}, []string{"action", "service"}),
+		successTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "uniqush_push",
+			Name:      "requests_success_total",
+			Help:      "Total number of requests that completed successfully.",
+		}, []string{"action", "service"}),
+		validationFails: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "uniqush_push",
+			Name:      "requests_validation_failures_total",
+			Help:      "Total number of requests rejected by Validate.",
+		}, []string{"action", "service"}),
+		databaseFails: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "uniqush_push",
+			Name:      "requests_database_failures_total",
+			Help:      "Total number of requests that failed a dbfront call.",
+		}, []string{"action", "service"}),
+		processDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "uniqush_push",
+			Name:      "process_duration_seconds",
+			Help:      "Latency of RequestProcessor.Process, from dispatch to req.Finish.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"action", "service"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "uniqush_push",
+			Name:      "requests_in_flight",
+			Help:      "Number of requests currently inside Process.",
+		}, []string{"action", "service"}),
+	}
+	prometheus.MustRegister(m.requestsTotal, m.successTotal, m.validationFails,
+		m.databaseFails, m.processDuration, m.inFlight)
+	return m
+}
+
+// ListenAndServe starts the embedded /metrics HTTP endpoint. It blocks, so
+// callers typically run it in its own goroutine from main.
+func (m *MetricsRegistry) ListenAndServe(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	return http.ListenAndServe(addr, mux)
+}
+
+func (m *MetricsRegistry) success(action, service string) {
+	if m == nil {
+		return
+	}
+	m.successTotal.WithLabelValues(action, service).Inc()
+}
+
+func (m *MetricsRegistry) validationFailure(action, service string) {
+	if m == nil {
+		return
+	}
+	m.validationFails.WithLabelValues(action, service).Inc()
+}
+
+func (m *MetricsRegistry) databaseFailure(action, service string) {
+	if m == nil {
+		return
+	}
+	m.databaseFails.WithLabelValues(action, service).Inc()
+}
+
+// metricsSetter is the setter embedded by every processor that reports
+// outcomes, following the same pattern as logSetter and databaseSetter. The
+// action name is fixed per processor type, so it is captured once here
+// rather than threaded through every Process call.
+type metricsSetter struct {
+	metrics *MetricsRegistry
+	action  string
+}
+
+func (m *metricsSetter) SetMetrics(metrics *MetricsRegistry, action string) {
+	m.metrics = metrics
+	m.action = action
+}
+
+// metricsSettable is implemented by every processor that embeds
+// metricsSetter. BuildDispatchTable type-asserts against it so the
+// success/validationFailure/databaseFailure counters a processor reports
+// itself get wired up the same way the outer WithMetrics wrapper does.
+type metricsSettable interface {
+	SetMetrics(metrics *MetricsRegistry, action string)
+}
+
+// instrumentedProcessor decorates a RequestProcessor with request-count,
+// in-flight-gauge and latency-histogram tracking. It forwards SetLogger and
+// Process to the wrapped processor unchanged, so the cross-cutting
+// bookkeeping lives in one place instead of in every processor's Process
+// method.
+type instrumentedProcessor struct {
+	RequestProcessor
+	action  string
+	metrics *MetricsRegistry
+}
+
+// WithMetrics wraps p so every call to Process is counted and timed under
+// metrics, labelled with action.
+func WithMetrics(p RequestProcessor, action string, metrics *MetricsRegistry) RequestProcessor {
+	return &instrumentedProcessor{RequestProcessor: p, action: action, metrics: metrics}
+}
+
+func (ip *instrumentedProcessor) Process(req *Request) {
+	service := req.Service
+	ip.metrics.requestsTotal.WithLabelValues(ip.action, service).Inc()
+	ip.metrics.inFlight.WithLabelValues(ip.action, service).Inc()
+	defer ip.metrics.inFlight.WithLabelValues(ip.action, service).Dec()
+
+	start := time.Now()
+	defer func() {
+		ip.metrics.processDuration.WithLabelValues(ip.action, service).Observe(time.Since(start).Seconds())
+	}()
+
+	ip.RequestProcessor.Process(req)
+}
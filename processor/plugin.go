@@ -0,0 +1,96 @@
+/*
+ * Copyright 2011 Nan Deng
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package processor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"plugin"
+
+	"github.com/uniqush/pushdb"
+	"github.com/uniqush/uniqush-push/log"
+)
+
+// Exported symbol names every out-of-tree plugin must provide.
+const (
+	pluginActionSymbol  = "Action"
+	pluginFactorySymbol = "NewProcessor"
+)
+
+// LoadPlugins opens every *.so file in dir with the Go plugin package and
+// registers the RequestProcessor it provides. Each plugin must export:
+//
+//	var Action processor.Action
+//	func NewProcessor(logger log.Logger, dbfront pushdb.PushDatabase) processor.RequestProcessor
+//
+// A plugin that fails to open, is missing a symbol, or collides with an
+// already-registered Action is skipped; its error is included in the
+// returned slice so main can log it without aborting the rest of the scan.
+func LoadPlugins(dir string) []error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return []error{fmt.Errorf("processor: reading plugin dir %s: %w", dir, err)}
+	}
+
+	var errs []error
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".so" {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if err := loadPlugin(path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+func loadPlugin(path string) error {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return fmt.Errorf("processor: opening plugin %s: %w", path, err)
+	}
+
+	actionSym, err := p.Lookup(pluginActionSymbol)
+	if err != nil {
+		return fmt.Errorf("processor: plugin %s missing %s: %w", path, pluginActionSymbol, err)
+	}
+	action, ok := actionSym.(*Action)
+	if !ok {
+		return fmt.Errorf("processor: plugin %s: %s is not a *processor.Action", path, pluginActionSymbol)
+	}
+
+	factorySym, err := p.Lookup(pluginFactorySymbol)
+	if err != nil {
+		return fmt.Errorf("processor: plugin %s missing %s: %w", path, pluginFactorySymbol, err)
+	}
+	// A plugin's exported "func NewProcessor(...) ..." has the unnamed
+	// function type below, not Factory itself, so asserting straight to
+	// Factory always fails; assert to the literal signature and convert.
+	factoryFn, ok := factorySym.(func(log.Logger, pushdb.PushDatabase) RequestProcessor)
+	if !ok {
+		return fmt.Errorf("processor: plugin %s: %s has the wrong signature", path, pluginFactorySymbol)
+	}
+	factory := Factory(factoryFn)
+
+	if err := Register(*action, factory); err != nil {
+		return fmt.Errorf("processor: plugin %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright 2011 Nan Deng
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadPluginsMissingDir(t *testing.T) {
+	errs := LoadPlugins(filepath.Join(t.TempDir(), "does-not-exist"))
+	if len(errs) != 1 {
+		t.Fatalf("LoadPlugins on a missing dir: got %d errors, want 1", len(errs))
+	}
+}
+
+func TestLoadPluginsSkipsNonSharedObjects(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a plugin"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "subdir.so"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if errs := LoadPlugins(dir); len(errs) != 0 {
+		t.Fatalf("LoadPlugins over non-.so entries: got %v, want no errors", errs)
+	}
+}
+
+func TestLoadPluginsOpenFailure(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "bad.so"), []byte("not an ELF shared object"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := LoadPlugins(dir)
+	if len(errs) != 1 {
+		t.Fatalf("LoadPlugins over a corrupt .so: got %d errors, want 1", len(errs))
+	}
+}
@@ -0,0 +1,54 @@
+/*
+ * Copyright 2011 Nan Deng
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package processor
+
+import (
+	"github.com/uniqush/pushdb"
+	"github.com/uniqush/uniqush-push/log"
+)
+
+// RequestProcessor handles one kind of Request. uniqush-push dispatches
+// every incoming admin/subscriber call to the RequestProcessor registered
+// for its Action.
+type RequestProcessor interface {
+	SetLogger(logger log.Logger)
+	Process(req *Request)
+}
+
+// Factory builds the RequestProcessor for one Action, given the shared
+// logger and database front-end main constructs at startup.
+type Factory func(logger log.Logger, dbfront pushdb.PushDatabase) RequestProcessor
+
+// ProcessorMiddleware wraps a RequestProcessor with a cross-cutting
+// concern (metrics, retry, auth, tracing, ...) and returns the wrapped
+// processor, so several concerns can be composed around every registered
+// processor the same way regardless of which ones apply:
+//
+//	p = processor.Chain(base, poolMiddleware, metricsMiddleware)
+//
+// where each argument after base is itself a ProcessorMiddleware.
+type ProcessorMiddleware func(RequestProcessor) RequestProcessor
+
+// Chain applies mws to p in order, so the first middleware is outermost:
+// Chain(p, a, b).Process calls a(b(p)).Process.
+func Chain(p RequestProcessor, mws ...ProcessorMiddleware) RequestProcessor {
+	for i := len(mws) - 1; i >= 0; i-- {
+		p = mws[i](p)
+	}
+	return p
+}
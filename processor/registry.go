@@ -0,0 +1,73 @@
+/*
+ * Copyright 2011 Nan Deng
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package processor
+
+import (
+	"fmt"
+	"sync"
+)
+
+var (
+	registryMu sync.Mutex
+	registry   = map[Action]Factory{}
+)
+
+// Register associates action with factory, so main's BuildDispatchTable can later construct
+// the RequestProcessor for it. In-tree processors call this from an
+// init(); out-of-tree ones arrive the same way via LoadPlugins. Register
+// returns an error instead of panicking so a misbehaving plugin can't take
+// the whole process down; use MustRegister for in-tree registrations
+// where a collision is a programming error.
+func Register(action Action, factory Factory) error {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, exists := registry[action]; exists {
+		return fmt.Errorf("processor: action %v already registered", action)
+	}
+	registry[action] = factory
+	return nil
+}
+
+// MustRegister is Register, panicking on collision. In-tree processors
+// should use this in their init() funcs.
+func MustRegister(action Action, factory Factory) {
+	if err := Register(action, factory); err != nil {
+		panic(err)
+	}
+}
+
+// Lookup returns the Factory registered for action, if any.
+func Lookup(action Action) (Factory, bool) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	factory, ok := registry[action]
+	return factory, ok
+}
+
+// Actions returns every Action currently registered, for main to build its
+// dispatch table by iterating the registry rather than naming each
+// processor directly.
+func Actions() []Action {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	actions := make([]Action, 0, len(registry))
+	for a := range registry {
+		actions = append(actions, a)
+	}
+	return actions
+}
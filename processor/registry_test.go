@@ -0,0 +1,66 @@
+/*
+ * Copyright 2011 Nan Deng
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package processor
+
+import (
+	"testing"
+
+	"github.com/uniqush/pushdb"
+	"github.com/uniqush/uniqush-push/log"
+)
+
+func stubFactory(logger log.Logger, dbfront pushdb.PushDatabase) RequestProcessor {
+	return nil
+}
+
+func TestRegisterCollision(t *testing.T) {
+	const action Action = 9001
+	defer delete(registry, action)
+
+	if err := Register(action, stubFactory); err != nil {
+		t.Fatalf("first Register: got %v, want nil", err)
+	}
+	if err := Register(action, stubFactory); err == nil {
+		t.Fatal("second Register for the same action: got nil error, want a collision error")
+	}
+
+	factory, ok := Lookup(action)
+	if !ok || factory == nil {
+		t.Fatal("Lookup after a rejected collision: want the original factory still registered")
+	}
+}
+
+func TestMustRegisterPanicsOnCollision(t *testing.T) {
+	const action Action = 9002
+	defer delete(registry, action)
+
+	MustRegister(action, stubFactory)
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("MustRegister collision: want a panic, got none")
+		}
+	}()
+	MustRegister(action, stubFactory)
+}
+
+func TestLookupUnregisteredAction(t *testing.T) {
+	if _, ok := Lookup(Action(9003)); ok {
+		t.Fatal("Lookup of an unregistered action: want ok=false")
+	}
+}
@@ -0,0 +1,144 @@
+/*
+ * Copyright 2011 Nan Deng
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package processor holds the contract between uniqush-push's dispatcher
+// and the code that acts on a Request: the Request and Action types,
+// the RequestProcessor interface, and the registry that maps an Action to
+// the RequestProcessor that handles it. Splitting this out of package main
+// is what lets out-of-tree Go plugins implement RequestProcessor without
+// importing package main, which the Go toolchain forbids.
+package processor
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/uniqush/pushdb"
+)
+
+// Action identifies what a Request asks a RequestProcessor to do.
+type Action int
+
+const (
+	InvalidAction Action = iota
+	AddPushServiceProviderAction
+	RemovePushServiceProviderAction
+	SubscribeAction
+	UnsubscribeAction
+	PushNotificationAction
+)
+
+func (a Action) String() string {
+	switch a {
+	case AddPushServiceProviderAction:
+		return "AddPushServiceProvider"
+	case RemovePushServiceProviderAction:
+		return "RemovePushServiceProvider"
+	case SubscribeAction:
+		return "Subscribe"
+	case UnsubscribeAction:
+		return "Unsubscribe"
+	case PushNotificationAction:
+		return "PushNotification"
+	default:
+		return "Invalid"
+	}
+}
+
+// Request is the unit of work handed to a RequestProcessor. One Request is
+// built per incoming admin/subscriber call and carries everything a
+// processor needs to validate, act on, and respond to it.
+type Request struct {
+	ID                  string
+	Action              Action
+	Service             string
+	Subscribers         []string
+	PushServiceProvider pushdb.PushServiceProvider
+	DeliveryPoint       pushdb.DeliveryPoint
+
+	// AuthToken is the raw credential presented with the request (an HMAC
+	// signature or a JWT bearer token, depending on which auth.Authenticator
+	// is configured). AuthMetadata carries out-of-band pieces an
+	// Authenticator needs alongside the token, e.g. the timestamp and nonce
+	// an HMAC signature was computed over.
+	AuthToken    string
+	AuthMetadata map[string]string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	respOnce sync.Once
+	response chan error
+}
+
+// NewRequest builds a Request with no deadline. Callers that have one
+// (an HTTP handler with a client timeout, an admin CLI --timeout flag)
+// should follow up with WithDeadline.
+func NewRequest(id string, action Action) *Request {
+	return &Request{
+		ID:       id,
+		Action:   action,
+		ctx:      context.Background(),
+		response: make(chan error, 1),
+	}
+}
+
+// ActionName returns the human-readable name of r.Action.
+func (r *Request) ActionName() string {
+	return r.Action.String()
+}
+
+// Context returns the Request's context, Background if none was set.
+func (r *Request) Context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
+}
+
+// WithDeadline attaches a deadline to the Request. A time.AfterFunc-backed
+// timer closes the Done channel of the Request's Context at t, the same pattern
+// netstack's deadlineTimer uses, so processors can select on it alongside
+// a dbfront call instead of polling.
+func (r *Request) WithDeadline(t time.Time) {
+	ctx, cancel := context.WithDeadline(r.Context(), t)
+	r.ctx = ctx
+	r.cancel = cancel
+}
+
+// Respond records the processor's outcome. Only the first call per Request
+// is recorded; later calls are dropped. RequestProcessor implementations
+// call it at most once, before their deferred Finish runs; some return
+// paths (e.g. a successful RemovePushServiceProvider) don't call it at all.
+func (r *Request) Respond(err error) {
+	select {
+	case r.response <- err:
+	default:
+	}
+}
+
+// Finish marks the Request complete, releasing anything waiting on it and
+// cancelling its context. Processors call this via defer so it runs
+// exactly once regardless of which return path was taken.
+func (r *Request) Finish() {
+	r.respOnce.Do(func() {
+		if r.cancel != nil {
+			r.cancel()
+		}
+	})
+}
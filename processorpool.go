@@ -0,0 +1,54 @@
+/*
+ * Copyright 2011 Nan Deng
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/uniqush/uniqush-push/gopool"
+
+	. "github.com/uniqush/uniqush-push/processor"
+)
+
+// pooledProcessor decorates a RequestProcessor so Process runs on a
+// gopool.Pool worker instead of whatever goroutine dispatched the request.
+// This bounds the concurrency (and, via the pool's queue, the backlog) any
+// single action can consume.
+type pooledProcessor struct {
+	RequestProcessor
+	pool *gopool.Pool
+}
+
+// WithPool wraps p so every call to Process is submitted to pool rather
+// than executed synchronously.
+func WithPool(p RequestProcessor, pool *gopool.Pool) RequestProcessor {
+	return &pooledProcessor{RequestProcessor: p, pool: pool}
+}
+
+func (pp *pooledProcessor) Process(req *Request) {
+	inner := pp.RequestProcessor
+	err := pp.pool.Submit(func() {
+		inner.Process(req)
+	})
+	if err != nil {
+		// The pool didn't accept the task, so nothing else will call
+		// req.Finish for it; that's still our responsibility.
+		req.Respond(fmt.Errorf("[QueueFull] RequestId=%v %v", req.ID, err))
+		req.Finish()
+	}
+}
@@ -0,0 +1,43 @@
+/*
+ * Copyright 2011 Nan Deng
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"github.com/uniqush/uniqush-push/log"
+
+	. "github.com/uniqush/pushdb"
+	. "github.com/uniqush/uniqush-push/processor"
+)
+
+// init registers uniqush-push's built-in processors with the processor
+// registry. Out-of-tree processors (bulk-subscribe, tag-based routing,
+// message templating, ...) register the same way via LoadPlugins.
+func init() {
+	MustRegister(AddPushServiceProviderAction, func(logger log.Logger, dbfront PushDatabase) RequestProcessor {
+		return NewAddPushServiceProviderProcessor(logger, dbfront)
+	})
+	MustRegister(RemovePushServiceProviderAction, func(logger log.Logger, dbfront PushDatabase) RequestProcessor {
+		return NewRemovePushServiceProviderProcessor(logger, dbfront)
+	})
+	MustRegister(SubscribeAction, func(logger log.Logger, dbfront PushDatabase) RequestProcessor {
+		return NewSubscribeProcessor(logger, dbfront)
+	})
+	MustRegister(UnsubscribeAction, func(logger log.Logger, dbfront PushDatabase) RequestProcessor {
+		return NewUnsubscribeProcessor(logger, dbfront)
+	})
+}
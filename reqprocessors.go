@@ -18,42 +18,70 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"github.com/uniqush/log"
-	. "github.com/uniqush/pushdb"
 	"regexp"
+
+	"github.com/uniqush/uniqush-push/log"
+	"github.com/uniqush/uniqush-push/retry"
+
+	. "github.com/uniqush/pushdb"
+	. "github.com/uniqush/uniqush-push/processor"
 )
 
-type RequestProcessor interface {
-	SetLogger(logger *uniqushlog.Logger)
-	Process(req *Request)
+// deadlineExceeded reports whether req's context has already expired or
+// been cancelled, without blocking.
+func deadlineExceeded(req *Request) bool {
+	select {
+	case <-req.Context().Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// contextErr reports whether err is (or wraps) the context error a dbfront
+// call returns when req's deadline fires while the call is in flight; see
+// selectable in retrysetter.go. Callers route it through respondDeadlineExceeded
+// instead of treating it as an ordinary database failure.
+func contextErr(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)
+}
+
+// respondDeadlineExceeded logs and responds to req once its deadline has
+// passed. Callers still defer req.Finish(), which is what cancels the
+// Request's context in the first place.
+func respondDeadlineExceeded(logger log.Logger, req *Request) {
+	logger.Error("request deadline exceeded", log.RequestId(req.ID), log.Service(req.Service))
+	req.Respond(fmt.Errorf("[DeadlineExceeded] RequestId=%v %v", req.ID, req.Context().Err()))
 }
 
 type ActionPrinter struct {
-	logger *uniqushlog.Logger
+	logger log.Logger
 }
 
-func NewActionPrinter(logger *uniqushlog.Logger) RequestProcessor {
+func NewActionPrinter(logger log.Logger) RequestProcessor {
 	a := new(ActionPrinter)
 	a.logger = logger
 	return a
 }
 
-func (p *ActionPrinter) SetLogger(logger *uniqushlog.Logger) {
+func (p *ActionPrinter) SetLogger(logger log.Logger) {
 	p.logger = logger
 }
 
 func (p *ActionPrinter) Process(r *Request) {
-	p.logger.Debugf("Action: %d-%s, id: %s\n", r.Action, r.ActionName(), r.ID)
+	p.logger.Debug("action dispatched", log.Action(r.ActionName()), log.RequestId(r.ID))
 	r.Finish()
 	return
 }
 
 type logSetter struct {
-	logger *uniqushlog.Logger
+	logger log.Logger
 }
 
-func (l *logSetter) SetLogger(logger *uniqushlog.Logger) {
+func (l *logSetter) SetLogger(logger log.Logger) {
 	l.logger = logger
 }
 
@@ -98,13 +126,17 @@ type AddPushServiceProviderProcessor struct {
 	logSetter
 	databaseSetter
 	requestValidator
+	metricsSetter
+	retrySetter
+	authSetter
 }
 
-func NewAddPushServiceProviderProcessor(logger *uniqushlog.Logger, dbfront PushDatabase) RequestProcessor {
+func NewAddPushServiceProviderProcessor(logger log.Logger, dbfront PushDatabase) RequestProcessor {
 	ret := new(AddPushServiceProviderProcessor)
 	ret.SetLogger(logger)
 	ret.SetDatabase(dbfront)
 	ret.InitValidator()
+	ret.SetRetryPolicy(retry.DefaultPolicy())
 
 	return ret
 }
@@ -112,20 +144,44 @@ func NewAddPushServiceProviderProcessor(logger *uniqushlog.Logger, dbfront PushD
 func (p *AddPushServiceProviderProcessor) Process(req *Request) {
 	defer req.Finish()
 
+	if deadlineExceeded(req) {
+		respondDeadlineExceeded(p.logger, req)
+		return
+	}
+	if !p.authenticateOrRespond(p.logger, req.ActionName(), req) {
+		return
+	}
+
 	err := p.Validate(req)
 	if err != nil {
-		p.logger.Errorf("[AddPushServiceRequestFail] RequestId=%v %v", req.ID, err)
+		p.logger.Error("add push service provider request failed",
+			log.RequestId(req.ID), log.Service(req.Service), log.Err(err))
+		p.metrics.validationFailure(p.action, req.Service)
 		req.Respond(fmt.Errorf("[AddPushServiceRequestFail] RequestId=%v %v", req.ID, err))
 		return
 	}
-	err = p.dbfront.AddPushServiceProviderToService(req.Service, req.PushServiceProvider)
+	if deadlineExceeded(req) {
+		respondDeadlineExceeded(p.logger, req)
+		return
+	}
+	err = p.retryDatabaseOp(req.Context(), p.logger, req, func() error {
+		return p.dbfront.AddPushServiceProviderToService(req.Service, req.PushServiceProvider)
+	})
 	if err != nil {
-		p.logger.Errorf("[AddPushServiceRequestFail] RequestId=%v DatabaseError %v", req.ID, err)
+		if contextErr(err) {
+			respondDeadlineExceeded(p.logger, req)
+			return
+		}
+		p.logger.Error("add push service provider request failed",
+			log.RequestId(req.ID), log.Service(req.Service),
+			log.PushServiceProvider(req.PushServiceProvider.Name()), log.Err(err))
+		p.metrics.databaseFailure(p.action, req.Service)
 		req.Respond(fmt.Errorf("[AddPushServiceRequestFail] RequestId=%v DatabaseError %v", req.ID, err))
 		return
 	}
-	p.logger.Infof("[AddPushServiceRequest] RequestId=%v Success PushServiceProviderID=%s",
-		req.ID, req.PushServiceProvider.Name())
+	p.logger.Info("add push service provider request succeeded",
+		log.RequestId(req.ID), log.Service(req.Service), log.PushServiceProvider(req.PushServiceProvider.Name()))
+	p.metrics.success(p.action, req.Service)
 	e := fmt.Errorf("PushServiceProvider=%v Success!", req.PushServiceProvider.Name())
 	req.Respond(e)
 	return
@@ -135,14 +191,18 @@ type RemovePushServiceProviderProcessor struct {
 	logSetter
 	databaseSetter
 	requestValidator
+	metricsSetter
+	retrySetter
+	authSetter
 }
 
-func NewRemovePushServiceProviderProcessor(logger *uniqushlog.Logger,
+func NewRemovePushServiceProviderProcessor(logger log.Logger,
 	dbfront PushDatabase) RequestProcessor {
 	ret := new(RemovePushServiceProviderProcessor)
 	ret.SetLogger(logger)
 	ret.SetDatabase(dbfront)
 	ret.InitValidator()
+	ret.SetRetryPolicy(retry.DefaultPolicy())
 
 	return ret
 }
@@ -150,20 +210,45 @@ func NewRemovePushServiceProviderProcessor(logger *uniqushlog.Logger,
 func (p *RemovePushServiceProviderProcessor) Process(req *Request) {
 	defer req.Finish()
 
+	if deadlineExceeded(req) {
+		respondDeadlineExceeded(p.logger, req)
+		return
+	}
+	if !p.authenticateOrRespond(p.logger, req.ActionName(), req) {
+		return
+	}
+
 	err0 := p.Validate(req)
 	if err0 != nil {
-		p.logger.Errorf("[RemovePushServiceRequestFail] RequestId=%v %v", req.ID, err0)
+		p.logger.Error("remove push service provider request failed",
+			log.RequestId(req.ID), log.Service(req.Service), log.Err(err0))
+		p.metrics.validationFailure(p.action, req.Service)
 		req.Respond(fmt.Errorf("[RemovePushServiceRequestFail] RequestId=%v %v", req.ID, err0))
 		return
 	}
 
-	err := p.dbfront.RemovePushServiceProviderFromService(req.Service, req.PushServiceProvider)
+	if deadlineExceeded(req) {
+		respondDeadlineExceeded(p.logger, req)
+		return
+	}
+	err := p.retryDatabaseOp(req.Context(), p.logger, req, func() error {
+		return p.dbfront.RemovePushServiceProviderFromService(req.Service, req.PushServiceProvider)
+	})
 	if err != nil {
-		p.logger.Errorf("[RemovePushServiceRequestFail] RequestId=%v DatabaseError %v", req.ID, err)
+		if contextErr(err) {
+			respondDeadlineExceeded(p.logger, req)
+			return
+		}
+		p.logger.Error("remove push service provider request failed",
+			log.RequestId(req.ID), log.Service(req.Service),
+			log.PushServiceProvider(req.PushServiceProvider.Name()), log.Err(err))
+		p.metrics.databaseFailure(p.action, req.Service)
 		req.Respond(fmt.Errorf("[RemovePushServiceRequestFail] RequestId=%v DatabaseError %v", req.ID, err))
 		return
 	}
-	p.logger.Infof("[RemovePushServiceRequest] Success PushServiceProviderID=%s", req.PushServiceProvider.Name())
+	p.logger.Info("remove push service provider request succeeded",
+		log.Service(req.Service), log.PushServiceProvider(req.PushServiceProvider.Name()))
+	p.metrics.success(p.action, req.Service)
 	return
 }
 
@@ -171,13 +256,17 @@ type SubscribeProcessor struct {
 	logSetter
 	databaseSetter
 	requestValidator
+	metricsSetter
+	retrySetter
+	authSetter
 }
 
-func NewSubscribeProcessor(logger *uniqushlog.Logger, dbfront PushDatabase) RequestProcessor {
+func NewSubscribeProcessor(logger log.Logger, dbfront PushDatabase) RequestProcessor {
 	ret := new(SubscribeProcessor)
 	ret.SetLogger(logger)
 	ret.SetDatabase(dbfront)
 	ret.InitValidator()
+	ret.SetRetryPolicy(retry.DefaultPolicy())
 
 	return ret
 }
@@ -187,26 +276,53 @@ func (p *SubscribeProcessor) Process(req *Request) {
 	if len(req.Subscribers) == 0 {
 		return
 	}
+	if deadlineExceeded(req) {
+		respondDeadlineExceeded(p.logger, req)
+		return
+	}
+	if !p.authenticateOrRespond(p.logger, req.ActionName(), req) {
+		return
+	}
 
 	err0 := p.Validate(req)
 	if err0 != nil {
-		p.logger.Errorf("[SubscribeRequestFail] RequestId=%v %v", req.ID, err0)
+		p.logger.Error("subscribe request failed",
+			log.RequestId(req.ID), log.Service(req.Service), log.Err(err0))
+		p.metrics.validationFailure(p.action, req.Service)
 		req.Respond(fmt.Errorf("[SubscribeRequestFail] RequestId=%v %v", req.ID, err0))
 		return
 	}
 
-	psp, err := p.dbfront.AddDeliveryPointToService(req.Service,
-		req.Subscribers[0],
-		req.DeliveryPoint)
+	if deadlineExceeded(req) {
+		respondDeadlineExceeded(p.logger, req)
+		return
+	}
+	var psp PushServiceProvider
+	err := p.retryDatabaseOp(req.Context(), p.logger, req, func() error {
+		var opErr error
+		psp, opErr = p.dbfront.AddDeliveryPointToService(req.Service,
+			req.Subscribers[0],
+			req.DeliveryPoint)
+		return opErr
+	})
 	if err != nil || psp == nil {
-		p.logger.Errorf("[SubscribeRequestFail] RequestId=%v DatabaseError %v", req.ID, err)
+		if contextErr(err) {
+			respondDeadlineExceeded(p.logger, req)
+			return
+		}
+		p.logger.Error("subscribe request failed",
+			log.RequestId(req.ID), log.Service(req.Service),
+			log.Subscriber(req.Subscribers[0]), log.Err(err))
+		p.metrics.databaseFailure(p.action, req.Service)
 		req.Respond(fmt.Errorf("[SubscribeRequestFail] RequestId=%v DatabaseError %v", req.ID, err))
 		return
 	}
 	dpname := req.DeliveryPoint.Name()
 	pspname := psp.Name()
-	p.logger.Infof("[SubscribeRequest] RequestId=%v Success DeliveryPoint=%s PushServiceProvider=%s",
-		req.ID, dpname, pspname)
+	p.logger.Info("subscribe request succeeded",
+		log.RequestId(req.ID), log.Service(req.Service),
+		log.DeliveryPoint(dpname), log.PushServiceProvider(pspname))
+	p.metrics.success(p.action, req.Service)
 	e := fmt.Errorf("DeliveryPoint=%v Success!", dpname)
 	req.Respond(e)
 	return
@@ -216,13 +332,17 @@ type UnsubscribeProcessor struct {
 	logSetter
 	databaseSetter
 	requestValidator
+	metricsSetter
+	retrySetter
+	authSetter
 }
 
-func NewUnsubscribeProcessor(logger *uniqushlog.Logger, dbfront PushDatabase) RequestProcessor {
+func NewUnsubscribeProcessor(logger log.Logger, dbfront PushDatabase) RequestProcessor {
 	ret := new(UnsubscribeProcessor)
 	ret.SetLogger(logger)
 	ret.SetDatabase(dbfront)
 	ret.InitValidator()
+	ret.SetRetryPolicy(retry.DefaultPolicy())
 
 	return ret
 }
@@ -230,27 +350,51 @@ func NewUnsubscribeProcessor(logger *uniqushlog.Logger, dbfront PushDatabase) Re
 func (p *UnsubscribeProcessor) Process(req *Request) {
 	defer req.Finish()
 	if len(req.Subscribers) == 0 || req.DeliveryPoint == nil {
-		p.logger.Errorf("[UnSubscribeRequestFail] RequestId=%v Nil Pointer", req.ID)
+		p.logger.Error("unsubscribe request failed: nil pointer", log.RequestId(req.ID), log.Service(req.Service))
+		p.metrics.validationFailure(p.action, req.Service)
 		req.Respond(fmt.Errorf("[UnSubscribeRequestFail] RequestId=%v Nil Pointer", req.ID))
 		return
 
 	}
+	if deadlineExceeded(req) {
+		respondDeadlineExceeded(p.logger, req)
+		return
+	}
+	if !p.authenticateOrRespond(p.logger, req.ActionName(), req) {
+		return
+	}
 	err0 := p.Validate(req)
 	if err0 != nil {
-		p.logger.Errorf("[UnSubscribeRequestFail] RequestId=%v %v", req.ID, err0)
+		p.logger.Error("unsubscribe request failed",
+			log.RequestId(req.ID), log.Service(req.Service), log.Err(err0))
+		p.metrics.validationFailure(p.action, req.Service)
 		req.Respond(fmt.Errorf("[UnSubscribeRequestFail] RequestId=%v %v", req.ID, err0))
 		return
 	}
 
-	err := p.dbfront.RemoveDeliveryPointFromService(req.Service,
-		req.Subscribers[0],
-		req.DeliveryPoint)
+	if deadlineExceeded(req) {
+		respondDeadlineExceeded(p.logger, req)
+		return
+	}
+	err := p.retryDatabaseOp(req.Context(), p.logger, req, func() error {
+		return p.dbfront.RemoveDeliveryPointFromService(req.Service,
+			req.Subscribers[0],
+			req.DeliveryPoint)
+	})
 	if err != nil {
-		p.logger.Errorf("[UnSubscribeRequestFail] RequestId=%v DatabaseError %v", req.ID, err)
+		if contextErr(err) {
+			respondDeadlineExceeded(p.logger, req)
+			return
+		}
+		p.logger.Error("unsubscribe request failed",
+			log.RequestId(req.ID), log.Service(req.Service),
+			log.DeliveryPoint(req.DeliveryPoint.Name()), log.Err(err))
+		p.metrics.databaseFailure(p.action, req.Service)
 		req.Respond(fmt.Errorf("[UnSubscribeRequestFail] RequestId=%v DatabaseError %v", req.ID, err))
 		return
 	}
-	p.logger.Infof("[UnsubscribeRequest] RequestId=%v Success DeliveryPoint=%s",
-		req.ID, req.DeliveryPoint.Name())
+	p.logger.Info("unsubscribe request succeeded",
+		log.RequestId(req.ID), log.Service(req.Service), log.DeliveryPoint(req.DeliveryPoint.Name()))
+	p.metrics.success(p.action, req.Service)
 	return
 }
@@ -0,0 +1,131 @@
+/*
+ * Copyright 2011 Nan Deng
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+// Package retry implements exponential backoff with full jitter for the
+// transient failures uniqush-push's processors see from the pushdb layer
+// (e.g. a Redis connection hiccup), without retrying errors that will never
+// succeed no matter how many times they're tried (validation errors,
+// "already exists", and the like).
+package retry
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// Policy configures exponential backoff with full jitter:
+//
+//	sleep = rand(0, min(MaxInterval, InitialInterval * Multiplier^attempt)) * RandomizationFactor
+//
+// A RandomizationFactor of 1.0 gives plain full jitter; 0 makes every
+// sleep zero. The zero value is not usable; build one with NewPolicy or DefaultPolicy.
+type Policy struct {
+	InitialInterval     time.Duration
+	Multiplier          float64
+	MaxInterval         time.Duration
+	MaxElapsedTime      time.Duration
+	RandomizationFactor float64
+}
+
+// DefaultPolicy returns the backoff policy uniqush-push uses when no
+// override is configured: 100ms initial, doubling, capped at 10s, giving up
+// after 30s.
+func DefaultPolicy() Policy {
+	return Policy{
+		InitialInterval:     100 * time.Millisecond,
+		Multiplier:          2,
+		MaxInterval:         10 * time.Second,
+		MaxElapsedTime:      30 * time.Second,
+		RandomizationFactor: 1.0,
+	}
+}
+
+// NextBackOff returns the sleep duration before retry attempt n (0-based),
+// with full jitter: a uniform random duration between 0 and the capped
+// exponential interval, scaled by RandomizationFactor.
+func (p Policy) NextBackOff(attempt int) time.Duration {
+	interval := float64(p.InitialInterval) * pow(p.Multiplier, attempt)
+	if max := float64(p.MaxInterval); interval > max {
+		interval = max
+	}
+	if interval <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Float64() * interval * p.RandomizationFactor)
+}
+
+func pow(base float64, exp int) float64 {
+	result := 1.0
+	for i := 0; i < exp; i++ {
+		result *= base
+	}
+	return result
+}
+
+// Transient is implemented by errors that are worth retrying, e.g. a Redis
+// network error returned by the pushdb layer.
+type Transient interface {
+	Transient() bool
+}
+
+// IsTransient reports whether err should be retried. It returns false for
+// nil and for any error that doesn't opt in via the Transient interface, so
+// validation errors and "already exists" errors are never retried.
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if t, ok := err.(Transient); ok {
+		return t.Transient()
+	}
+	return false
+}
+
+// OnRetry is called after each failed, transient attempt, before sleeping
+// for the returned backoff. Implementations typically log the attempt
+// number and next sleep.
+type OnRetry func(attempt int, err error, sleep time.Duration)
+
+// Do runs op, retrying on transient errors according to p until op
+// succeeds, an error is non-transient, MaxElapsedTime elapses, or ctx is
+// done. It returns the last error op returned, or ctx.Err() if ctx is done
+// while waiting to retry.
+func (p Policy) Do(ctx context.Context, op func() error, onRetry OnRetry) error {
+	start := time.Now()
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = op()
+		if !IsTransient(err) {
+			return err
+		}
+		if d := time.Since(start); d >= p.MaxElapsedTime {
+			return err
+		}
+		sleep := p.NextBackOff(attempt)
+		if onRetry != nil {
+			onRetry(attempt, err, sleep)
+		}
+		timer := time.NewTimer(sleep)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
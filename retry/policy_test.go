@@ -0,0 +1,122 @@
+/*
+ * Copyright 2011 Nan Deng
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type transientErr struct{ error }
+
+func (transientErr) Transient() bool { return true }
+
+func TestDoStopsOnNonTransientError(t *testing.T) {
+	p := Policy{InitialInterval: time.Millisecond, Multiplier: 2, MaxInterval: time.Millisecond, MaxElapsedTime: time.Second}
+	var calls int
+	want := errors.New("permanent")
+	err := p.Do(context.Background(), func() error {
+		calls++
+		return want
+	}, nil)
+
+	if err != want {
+		t.Fatalf("Do: got %v, want %v", err, want)
+	}
+	if calls != 1 {
+		t.Fatalf("op calls: got %d, want 1 (no retry for a non-transient error)", calls)
+	}
+}
+
+func TestDoRetriesTransientErrorUntilSuccess(t *testing.T) {
+	p := Policy{InitialInterval: time.Millisecond, Multiplier: 1, MaxInterval: time.Millisecond, MaxElapsedTime: time.Second}
+	var calls int
+	err := p.Do(context.Background(), func() error {
+		calls++
+		if calls < 3 {
+			return transientErr{errors.New("transient")}
+		}
+		return nil
+	}, nil)
+
+	if err != nil {
+		t.Fatalf("Do: got %v, want nil", err)
+	}
+	if calls != 3 {
+		t.Fatalf("op calls: got %d, want 3", calls)
+	}
+}
+
+func TestDoGivesUpAfterMaxElapsedTime(t *testing.T) {
+	p := Policy{InitialInterval: time.Millisecond, Multiplier: 1, MaxInterval: time.Millisecond, MaxElapsedTime: 20 * time.Millisecond}
+	want := transientErr{errors.New("always transient")}
+	err := p.Do(context.Background(), func() error {
+		return want
+	}, nil)
+
+	if err != want {
+		t.Fatalf("Do after MaxElapsedTime: got %v, want %v", err, want)
+	}
+}
+
+func TestDoAbortsOnContextCancellation(t *testing.T) {
+	p := Policy{InitialInterval: time.Second, Multiplier: 1, MaxInterval: time.Second, MaxElapsedTime: time.Minute}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := p.Do(ctx, func() error {
+		return transientErr{errors.New("transient")}
+	}, nil)
+
+	if err != context.Canceled {
+		t.Fatalf("Do with a cancelled context: got %v, want context.Canceled", err)
+	}
+}
+
+func TestDoCallsOnRetryBeforeEachRetry(t *testing.T) {
+	p := Policy{InitialInterval: time.Millisecond, Multiplier: 1, MaxInterval: time.Millisecond, MaxElapsedTime: time.Second}
+	var attempts []int
+	calls := 0
+	p.Do(context.Background(), func() error {
+		calls++
+		if calls < 3 {
+			return transientErr{errors.New("transient")}
+		}
+		return nil
+	}, func(attempt int, err error, sleep time.Duration) {
+		attempts = append(attempts, attempt)
+	})
+
+	if len(attempts) != 2 {
+		t.Fatalf("onRetry calls: got %d, want 2", len(attempts))
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	if IsTransient(nil) {
+		t.Fatal("IsTransient(nil): want false")
+	}
+	if IsTransient(errors.New("plain")) {
+		t.Fatal("IsTransient(plain error): want false")
+	}
+	if !IsTransient(transientErr{errors.New("transient")}) {
+		t.Fatal("IsTransient(transient error): want true")
+	}
+}
@@ -0,0 +1,70 @@
+/*
+ * Copyright 2011 Nan Deng
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/uniqush/uniqush-push/log"
+	"github.com/uniqush/uniqush-push/retry"
+
+	. "github.com/uniqush/uniqush-push/processor"
+)
+
+// retrySetter is embedded by every processor that calls dbfront, following
+// the same pattern as logSetter and databaseSetter. The policy is loaded
+// once in main and shared across all processors.
+type retrySetter struct {
+	retryPolicy retry.Policy
+}
+
+func (r *retrySetter) SetRetryPolicy(policy retry.Policy) {
+	r.retryPolicy = policy
+}
+
+// retryDatabaseOp runs op under retryPolicy, retrying transient dbfront
+// errors with exponential backoff and full jitter. It logs each retry
+// attempt against logger and aborts early if req's context is done, either
+// between attempts or while op itself is still blocked in a dbfront call.
+func (r *retrySetter) retryDatabaseOp(ctx context.Context, logger log.Logger, req *Request, op func() error) error {
+	return r.retryPolicy.Do(ctx, selectable(ctx, op), func(attempt int, err error, sleep time.Duration) {
+		logger.Warn("retrying transient database error",
+			log.RequestId(req.ID), log.Service(req.Service),
+			log.F("Attempt", attempt), log.F("NextSleepMs", sleep.Milliseconds()), log.Err(err))
+	})
+}
+
+// selectable runs op on its own goroutine and returns as soon as either op
+// finishes or ctx is done, whichever comes first. pushdb's PushDatabase
+// methods take no context of their own, so this is how a deadline that
+// expires mid-call still gets honoured instead of only being checked
+// before/after each attempt; op keeps running in the background until it
+// returns, same as any other blocking call raced against a context.
+func selectable(ctx context.Context, op func() error) func() error {
+	return func() error {
+		done := make(chan error, 1)
+		go func() { done <- op() }()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-done:
+			return err
+		}
+	}
+}